@@ -0,0 +1,32 @@
+package models
+
+// DiscordMessageState is the persisted (org_id, rule_id, notifier_id) ->
+// message_id mapping backing the Discord notifier's "update existing
+// message" mode, stored in the alert_notification_state table.
+type DiscordMessageState struct {
+	Id         int64
+	OrgId      int64
+	RuleId     int64
+	NotifierId int64
+	MessageId  string
+}
+
+// GetDiscordMessageStateQuery looks up the message a prior notification for
+// this rule left behind, if any. Result is left nil when there is none.
+type GetDiscordMessageStateQuery struct {
+	OrgId      int64
+	RuleId     int64
+	NotifierId int64
+
+	Result *DiscordMessageState
+}
+
+// SetDiscordMessageStateCommand records the message id created for a rule's
+// first notification (or updates the one already on file) so later state
+// changes can edit it in place.
+type SetDiscordMessageStateCommand struct {
+	OrgId      int64
+	RuleId     int64
+	NotifierId int64
+	MessageId  string
+}
@@ -0,0 +1,54 @@
+package models
+
+import "io"
+
+// SendWebhookSync is a command to synchronously send a webhook request and
+// wait for it to complete, so the dispatcher can report failures (and,
+// since ResponseBody/ResponseHeader/ResponseStatusCode were added, so the
+// caller can inspect what the remote end returned - e.g. a notifier that
+// needs to read back an id assigned by the far side).
+type SendWebhookSync struct {
+	Url         string
+	User        string
+	Password    string
+	Body        string
+	HttpMethod  string
+	HttpHeader  map[string]string
+	ContentType string
+
+	// BodyReader, when set, is streamed into the request instead of Body,
+	// avoiding the extra buffering and copying a large string Body would
+	// need. BodyReaderFactory takes precedence over both: the dispatcher
+	// calls it once per request to obtain a fresh io.ReadCloser (unlike a
+	// plain io.Reader, a factory can produce the body again). The dispatcher
+	// does not retry a failed request.
+	BodyReader        io.Reader
+	BodyReaderFactory func() (io.ReadCloser, error)
+
+	ResponseStatusCode int
+	ResponseBody       string
+	ResponseHeader     map[string][]string
+}
+
+// SendWebhook sends a webhook request without exposing the response: the
+// dispatch waits for the request to complete and returns an error only when
+// the request could not be sent; an error status from the remote end is
+// logged, not returned, and the response body is discarded. It is used for
+// follow-up requests (e.g. PATCHing a previously sent message on a state
+// change) where the original notification has already succeeded.
+type SendWebhook struct {
+	Url         string
+	User        string
+	Password    string
+	Body        string
+	HttpMethod  string
+	HttpHeader  map[string]string
+	ContentType string
+
+	// BodyReader/BodyReaderFactory mirror SendWebhookSync's fields, so a
+	// caller that built one of these commands from a discordPayload (or
+	// anything else that streams a large body) can hand the other command
+	// the same reader/factory instead of being forced back onto Body.
+	BodyReader        io.Reader
+	BodyReaderFactory func() (io.ReadCloser, error)
+}
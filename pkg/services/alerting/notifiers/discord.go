@@ -2,11 +2,13 @@ package notifiers
 
 import (
 	"bytes"
+	"fmt"
 	"io"
-	"mime/multipart"
+	neturl "net/url"
 	"os"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/components/simplejson"
@@ -16,6 +18,10 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+// discordEmbedLimit is the maximum number of embeds Discord accepts in a
+// single message.
+const discordEmbedLimit = 10
+
 func init() {
 	alerting.RegisterNotifier(&alerting.NotifierPlugin{
 		Type:        "discord",
@@ -32,12 +38,93 @@ func init() {
           data-placement="right">
         </input>
         <info-popover mode="right-absolute">
-          Mention a group using @ or a user using <@ID> when notifying in a channel
+          Mention a group using @ or a user using <@ID> when notifying in a channel. Supports Go templates
+          with access to .EvalMatches, .Rule and .State.
         </info-popover>
       </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Username</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.username" placeholder="Grafana"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Avatar URL</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.avatar_url"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Embed Title</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.embed_title"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Embed Description</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.embed_description"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Embed URL</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.embed_url"></input>
+      </div>
       <div class="gf-form  max-width-30">
         <span class="gf-form-label width-10">Webhook URL</span>
-        <input type="text" required class="gf-form-input max-width-30" ng-model="ctrl.model.settings.url" placeholder="Discord webhook URL"></input>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.url" placeholder="Discord webhook URL"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Webhook URL File</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.url_file" placeholder="/var/run/secrets/discord/url"></input>
+        <info-popover mode="right-absolute">
+          Path to a file containing the webhook URL, re-read on every notification. Use this instead of
+          Webhook URL when the secret is mounted by Vault agent, a Kubernetes secret, or similar.
+        </info-popover>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Bot Token</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.bot_token" placeholder="Discord bot token"></input>
+        <info-popover mode="right-absolute">
+          Alternative to a webhook: posts via the Discord bot API using this bot's token, so one bot
+          application can notify many channels. Requires Channel ID and cannot be combined with Webhook URL.
+        </info-popover>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Channel ID</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.channel_id" placeholder="Discord channel ID"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Thread ID</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.thread_id" placeholder="Existing thread to post into"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Thread Name</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.thread_name" placeholder="Starts a new thread (forum channels)"></input>
+      </div>
+      <div class="gf-form max-width-30">
+        <gf-form-switch
+          class="gf-form"
+          label="Update existing message"
+          label-class="width-10"
+          checked="ctrl.model.settings.update_existing_message">
+        </gf-form-switch>
+        <info-popover mode="right-absolute">
+          Instead of posting a new message for every state change, edit the message posted for this rule's
+          last notification in place, so the channel keeps a single live message per incident.
+        </info-popover>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Mention Users</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.mention_users" placeholder="Comma-separated user IDs"></input>
+      </div>
+      <div class="gf-form  max-width-30">
+        <span class="gf-form-label width-10">Mention Roles</span>
+        <input type="text" class="gf-form-input max-width-30" ng-model="ctrl.model.settings.mention_roles" placeholder="Comma-separated role IDs"></input>
+      </div>
+      <div class="gf-form max-width-30">
+        <gf-form-switch
+          class="gf-form"
+          label="Mention @everyone"
+          label-class="width-10"
+          checked="ctrl.model.settings.mention_everyone">
+        </gf-form-switch>
+        <info-popover mode="right-absolute">
+          Whitelists @everyone/@here and the users/roles above via allowed_mentions, so an @everyone or role
+          mention typed into Message Content only pings when explicitly allowed here.
+        </info-popover>
       </div>
     `,
 	})
@@ -45,28 +132,150 @@ func init() {
 
 func NewDiscordNotifier(model *models.AlertNotification) (alerting.Notifier, error) {
 	content := model.Settings.Get("content").MustString()
+	username := model.Settings.Get("username").MustString()
+	avatarURL := model.Settings.Get("avatar_url").MustString()
+	embedTitle := model.Settings.Get("embed_title").MustString()
+	embedDescription := model.Settings.Get("embed_description").MustString()
+	embedURL := model.Settings.Get("embed_url").MustString()
 	url := model.Settings.Get("url").MustString()
-	if url == "" {
-		return nil, alerting.ValidationError{Reason: "Could not find webhook url property in settings"}
+	urlFile := model.Settings.Get("url_file").MustString()
+	botToken := model.Settings.Get("bot_token").MustString()
+	channelID := model.Settings.Get("channel_id").MustString()
+	threadID := model.Settings.Get("thread_id").MustString()
+	threadName := model.Settings.Get("thread_name").MustString()
+	updateExistingMessage := model.Settings.Get("update_existing_message").MustBool(false)
+	// mention_users/mention_roles are entered as comma-separated IDs; a JSON
+	// array of IDs is accepted as well.
+	idList := func(key string) []string {
+		if ids, err := model.Settings.Get(key).StringArray(); err == nil {
+			return ids
+		}
+		var ids []string
+		for _, id := range strings.Split(model.Settings.Get(key).MustString(), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	mentionUsers := idList("mention_users")
+	mentionRoles := idList("mention_roles")
+	mentionEveryone := model.Settings.Get("mention_everyone").MustBool(false)
+
+	usesWebhook := url != "" || urlFile != ""
+	usesBotAPI := botToken != ""
+
+	if usesWebhook && usesBotAPI {
+		return nil, alerting.ValidationError{Reason: "Cannot set both a webhook url (or url_file) and a bot_token, choose one authentication mode"}
+	}
+
+	if !usesWebhook && !usesBotAPI {
+		return nil, alerting.ValidationError{Reason: "Could not find webhook url, url_file or bot_token property in settings"}
+	}
+
+	if usesBotAPI && channelID == "" {
+		return nil, alerting.ValidationError{Reason: "Could not find channel_id property in settings, required when using bot_token"}
 	}
 
 	return &DiscordNotifier{
-		NotifierBase: NewNotifierBase(model),
-		Content:      content,
-		WebhookURL:   url,
-		log:          log.New("alerting.notifier.discord"),
+		NotifierBase:          NewNotifierBase(model),
+		NotifierId:            model.Id,
+		Content:               content,
+		Username:              username,
+		AvatarURL:             avatarURL,
+		EmbedTitle:            embedTitle,
+		EmbedDescription:      embedDescription,
+		EmbedURL:              embedURL,
+		WebhookURL:            url,
+		WebhookURLFile:        urlFile,
+		BotToken:              botToken,
+		ChannelID:             channelID,
+		ThreadID:              threadID,
+		ThreadName:            threadName,
+		UpdateExistingMessage: updateExistingMessage,
+		MentionUsers:          mentionUsers,
+		MentionRoles:          mentionRoles,
+		MentionEveryone:       mentionEveryone,
+		log:                   log.New("alerting.notifier.discord"),
 	}, nil
 }
 
 type DiscordNotifier struct {
 	NotifierBase
-	Content    string
-	WebhookURL string
-	log        log.Logger
+	NotifierId            int64
+	Content               string
+	Username              string
+	AvatarURL             string
+	EmbedTitle            string
+	EmbedDescription      string
+	EmbedURL              string
+	WebhookURL            string
+	WebhookURLFile        string
+	BotToken              string
+	ChannelID             string
+	ThreadID              string
+	ThreadName            string
+	UpdateExistingMessage bool
+	MentionUsers          []string
+	MentionRoles          []string
+	MentionEveryone       bool
+	log                   log.Logger
+}
+
+// discordTemplateData is exposed to the Go templates that render Content,
+// Username, AvatarURL and the per-embed fields. Match is only set while
+// rendering the embed for that specific EvalMatch.
+type discordTemplateData struct {
+	EvalMatches []*alerting.EvalMatch
+	Match       *alerting.EvalMatch
+	Rule        *alerting.Rule
+	State       string
+}
+
+// renderTemplate executes tmplText as a Go template against data. An empty
+// tmplText is treated as "no template configured" and returns "" without
+// error, so callers can fall back to their own default.
+func (this *DiscordNotifier) renderTemplate(tmplText string, data *discordTemplateData) (string, error) {
+	if tmplText == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("discord").Parse(tmplText)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// resolveWebhookURL returns the URL to post the webhook body to. When
+// WebhookURLFile is set it is re-read and trimmed on every call instead of
+// using WebhookURL, so a rotated secret (Vault agent, Kubernetes secret
+// mount) is picked up without restarting Grafana.
+func (this *DiscordNotifier) resolveWebhookURL() (string, error) {
+	if this.WebhookURLFile == "" {
+		return this.WebhookURL, nil
+	}
+
+	b, err := os.ReadFile(this.WebhookURLFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
 }
 
 func (this *DiscordNotifier) Notify(evalContext *alerting.EvalContext) error {
-	this.log.Info("Sending alert notification to", "webhook_url", this.WebhookURL)
+	if this.BotToken != "" {
+		this.log.Info("Sending alert notification to Discord channel", "channel_id", this.ChannelID)
+	} else {
+		this.log.Info("Sending alert notification to", "webhook_url", "<redacted>")
+	}
 
 	ruleUrl, err := evalContext.GetRuleUrl()
 	if err != nil {
@@ -74,123 +283,383 @@ func (this *DiscordNotifier) Notify(evalContext *alerting.EvalContext) error {
 		return err
 	}
 
-	bodyJSON := simplejson.New()
-	bodyJSON.Set("username", "Grafana")
-
-	if this.Content != "" {
-		bodyJSON.Set("content", this.Content)
+	data := &discordTemplateData{
+		EvalMatches: evalContext.EvalMatches,
+		Rule:        evalContext.Rule,
+		State:       string(evalContext.Rule.State),
 	}
 
-	fields := make([]map[string]interface{}, 0)
+	content, err := this.renderTemplate(this.Content, data)
+	if err != nil {
+		this.log.Error("Failed to render content template", "error", err)
+		return err
+	}
 
-	for _, evt := range evalContext.EvalMatches {
+	username, err := this.renderTemplate(this.Username, data)
+	if err != nil {
+		this.log.Error("Failed to render username template", "error", err)
+		return err
+	}
+	if username == "" {
+		username = "Grafana"
+	}
 
-		fields = append(fields, map[string]interface{}{
-			"name":   evt.Metric,
-			"value":  evt.Value.FullString(),
-			"inline": true,
-		})
+	avatarURL, err := this.renderTemplate(this.AvatarURL, data)
+	if err != nil {
+		this.log.Error("Failed to render avatar_url template", "error", err)
+		return err
 	}
 
-	footer := map[string]interface{}{
-		"text":     "Grafana v" + setting.BuildVersion,
-		"icon_url": "https://grafana.com/assets/img/fav32.png",
+	embedBatches, err := this.buildEmbedBatches(evalContext, ruleUrl, data)
+	if err != nil {
+		this.log.Error("Failed to build Discord embeds", "error", err)
+		return err
 	}
 
-	color, _ := strconv.ParseInt(strings.TrimLeft(evalContext.GetStateModel().Color, "#"), 16, 0)
+	for batchIndex, embeds := range embedBatches {
+		payload := newDiscordPayload(username).WithAvatarURL(avatarURL)
 
-	embed := simplejson.New()
-	embed.Set("title", evalContext.GetNotificationTitle())
-	//Discord takes integer for color
-	embed.Set("color", color)
-	embed.Set("url", ruleUrl)
-	embed.Set("description", evalContext.Rule.Message)
-	embed.Set("type", "rich")
-	embed.Set("fields", fields)
-	embed.Set("footer", footer)
+		for _, embed := range embeds {
+			payload = payload.WithEmbed(embed)
+		}
 
-	var image map[string]interface{}
-	var embeddedImage = false
+		// Always set allowed_mentions, even when none of mention_users,
+		// mention_roles or mention_everyone are configured: Discord's
+		// default (no allowed_mentions field) honors every mention found
+		// in content, which is exactly what this setting exists to prevent.
+		payload = payload.WithMentions(this.MentionUsers, this.MentionRoles, this.MentionEveryone)
+
+		// Only the first message in the group carries the free-form
+		// content, the thread_name and the action buttons; later batches
+		// are pure embed continuations of the same alert.
+		if batchIndex == 0 {
+			if content != "" {
+				payload = payload.WithContent(content)
+			}
+
+			if this.ThreadName != "" && this.ThreadID == "" {
+				payload = payload.WithThreadName(this.ThreadName)
+			}
+
+			payload = payload.WithComponents(buildActionComponents(ruleUrl, evalContext.ImagePublicUrl))
+
+			if evalContext.ImagePublicUrl == "" {
+				open, err := openImageFile(evalContext.ImageOnDiskPath)
+				if err != nil {
+					this.log.Error("failed to stat rendered panel image", "error", err)
+					return err
+				}
+				if open != nil {
+					payload = payload.WithAttachment("graph.png", open)
+				}
+			}
+		}
 
-	if evalContext.ImagePublicUrl != "" {
-		image = map[string]interface{}{
-			"url": evalContext.ImagePublicUrl,
+		body, bodyReaderFactory, contentType, err := payload.Marshal()
+		if err != nil {
+			this.log.Error("failed to build Discord payload", "error", err)
+			return err
 		}
-		embed.Set("image", image)
-	} else {
-		image = map[string]interface{}{
-			"url": "attachment://graph.png",
+
+		cmd := &models.SendWebhookSync{
+			HttpMethod:        "POST",
+			ContentType:       contentType,
+			Body:              body,
+			BodyReaderFactory: bodyReaderFactory,
 		}
-		embed.Set("image", image)
-		embeddedImage = true
-	}
 
-	bodyJSON.Set("embeds", []interface{}{embed})
+		if this.BotToken != "" {
+			cmd.Url = "https://discord.com/api/v10/channels/" + this.ChannelID + "/messages"
+			cmd.HttpHeader = map[string]string{"Authorization": "Bot " + this.BotToken}
 
-	json, _ := bodyJSON.MarshalJSON()
+			if err := bus.DispatchCtx(evalContext.Ctx, cmd); err != nil {
+				this.log.Error("Failed to send notification to Discord", "error", err)
+				return err
+			}
 
-	cmd := &models.SendWebhookSync{
-		Url:         this.WebhookURL,
-		HttpMethod:  "POST",
-		ContentType: "application/json",
-	}
+			continue
+		}
 
-	if !embeddedImage {
-		cmd.Body = string(json)
-	} else {
-		err := this.embedImage(cmd, evalContext.ImageOnDiskPath, json)
+		webhookURL, err := this.resolveWebhookURL()
 		if err != nil {
-			this.log.Error("failed to embed image", "error", err)
+			this.log.Error("Failed to resolve Discord webhook url", "error", err)
 			return err
 		}
-	}
 
-	if err := bus.DispatchCtx(evalContext.Ctx, cmd); err != nil {
-		this.log.Error("Failed to send notification to Discord", "error", err)
-		return err
+		if this.UpdateExistingMessage && batchIndex == 0 {
+			if err := this.notifyWithMessageEdit(evalContext, webhookURL, payload, cmd); err != nil {
+				this.log.Error("Failed to send notification to Discord", "error", err)
+				return err
+			}
+
+			continue
+		}
+
+		cmd.Url, err = appendQuery(webhookURL, map[string]string{"thread_id": this.ThreadID})
+		if err != nil {
+			this.log.Error("Failed to build Discord webhook url", "error", err)
+			return err
+		}
+
+		if err := bus.DispatchCtx(evalContext.Ctx, cmd); err != nil {
+			this.log.Error("Failed to send notification to Discord", "error", err)
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (this *DiscordNotifier) embedImage(cmd *models.SendWebhookSync, imagePath string, existingJSONBody []byte) error {
-	f, err := os.Open(imagePath)
-	defer f.Close()
+// notifyWithMessageEdit implements the "update existing message" mode: the
+// first notification for a rule posts the message with ?wait=true to learn
+// its id, and every later state change PATCHes that same message instead of
+// posting a new one, so the channel keeps a single live message per
+// incident that transitions color/title as the rule's state changes.
+func (this *DiscordNotifier) notifyWithMessageEdit(evalContext *alerting.EvalContext, webhookURL string, payload *discordPayload, cmd *models.SendWebhookSync) error {
+	state, err := this.getMessageState(evalContext)
 	if err != nil {
-		if os.IsNotExist(err) {
-			cmd.Body = string(existingJSONBody)
-			return nil
+		return err
+	}
+
+	if state == nil {
+		url, err := appendQuery(webhookURL, map[string]string{"thread_id": this.ThreadID, "wait": "true"})
+		if err != nil {
+			return err
 		}
-		if !os.IsNotExist(err) {
+		cmd.Url = url
+
+		if err := bus.DispatchCtx(evalContext.Ctx, cmd); err != nil {
 			return err
 		}
-	}
 
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
+		messageID, err := extractMessageID(cmd.ResponseBody)
+		if err != nil {
+			this.log.Warn("Could not read Discord message id from response, next notification will post a new message", "error", err)
+			return nil
+		}
+
+		return this.setMessageState(evalContext, messageID)
+	}
 
-	fw, err := w.CreateFormField("payload_json")
+	webhookID, webhookToken, err := parseWebhookURL(webhookURL)
 	if err != nil {
 		return err
 	}
 
-	if _, err = fw.Write([]byte(string(existingJSONBody))); err != nil {
+	patchURL, err := appendQuery(
+		"https://discord.com/api/v10/webhooks/"+webhookID+"/"+webhookToken+"/messages/"+state.MessageId,
+		map[string]string{"thread_id": this.ThreadID},
+	)
+	if err != nil {
 		return err
 	}
 
-	fw, err = w.CreateFormFile("file", "graph.png")
+	// The edit endpoint doesn't accept username/avatar_url/thread_name -
+	// those only apply when a webhook first creates a message - so marshal a
+	// dedicated edit payload instead of reusing the one built for the POST.
+	editBody, editBodyReaderFactory, editContentType, err := payload.AsEditPayload().Marshal()
 	if err != nil {
 		return err
 	}
 
-	if _, err = io.Copy(fw, f); err != nil {
-		return err
+	patchCmd := &models.SendWebhook{
+		Url:               patchURL,
+		HttpMethod:        "PATCH",
+		ContentType:       editContentType,
+		Body:              editBody,
+		BodyReaderFactory: editBodyReaderFactory,
 	}
 
-	w.Close()
+	return bus.DispatchCtx(evalContext.Ctx, patchCmd)
+}
 
-	cmd.Body = b.String()
-	cmd.ContentType = w.FormDataContentType()
+func (this *DiscordNotifier) getMessageState(evalContext *alerting.EvalContext) (*models.DiscordMessageState, error) {
+	query := &models.GetDiscordMessageStateQuery{
+		OrgId:      evalContext.Rule.OrgId,
+		RuleId:     evalContext.Rule.Id,
+		NotifierId: this.NotifierId,
+	}
 
-	return nil
+	if err := bus.DispatchCtx(evalContext.Ctx, query); err != nil {
+		return nil, err
+	}
+
+	return query.Result, nil
+}
+
+func (this *DiscordNotifier) setMessageState(evalContext *alerting.EvalContext, messageID string) error {
+	cmd := &models.SetDiscordMessageStateCommand{
+		OrgId:      evalContext.Rule.OrgId,
+		RuleId:     evalContext.Rule.Id,
+		NotifierId: this.NotifierId,
+		MessageId:  messageID,
+	}
+
+	return bus.DispatchCtx(evalContext.Ctx, cmd)
+}
+
+// appendQuery merges params into rawURL's query string, skipping empty
+// values, so optional settings like thread_id don't show up as "".
+func appendQuery(rawURL string, params map[string]string) (string, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// parseWebhookURL extracts the webhook id and token from a Discord webhook
+// url of the form ".../webhooks/{id}/{token}", as needed to address the
+// PATCH-message-by-id endpoint.
+func parseWebhookURL(webhookURL string) (id string, token string, err error) {
+	parts := strings.Split(strings.TrimRight(webhookURL, "/"), "/")
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("unrecognized Discord webhook url: %s", webhookURL)
+	}
+
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// extractMessageID reads the "id" field Discord returns for a ?wait=true
+// webhook POST.
+func extractMessageID(responseBody string) (string, error) {
+	js, err := simplejson.NewJson([]byte(responseBody))
+	if err != nil {
+		return "", err
+	}
+
+	id, err := js.Get("id").String()
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("empty message id in Discord response")
+	}
+
+	return id, nil
+}
+
+// buildEmbedBatches renders one embed per EvalMatch (or a single embed when
+// there are no matches) and groups them into batches of at most
+// discordEmbedLimit, so a single rule with many firing series still
+// respects Discord's per-message embed cap.
+//
+// This is per-rule batching only: Notify is called once per *alerting.Rule,
+// so unlike Alertmanager's cross-rule grouping there is no batching of
+// distinct rules into one message here. The legacy per-rule notifier
+// architecture (one Notify call per firing/resolved rule) doesn't give this
+// notifier visibility into other rules' state to group across, so true
+// Alertmanager-style grouping is out of scope for this notifier as written.
+func (this *DiscordNotifier) buildEmbedBatches(evalContext *alerting.EvalContext, ruleUrl string, data *discordTemplateData) ([][]interface{}, error) {
+	color, _ := strconv.ParseInt(strings.TrimLeft(evalContext.GetStateModel().Color, "#"), 16, 0)
+
+	footer := map[string]interface{}{
+		"text":     "Grafana v" + setting.BuildVersion,
+		"icon_url": "https://grafana.com/assets/img/fav32.png",
+	}
+
+	matches := evalContext.EvalMatches
+	if len(matches) == 0 {
+		matches = []*alerting.EvalMatch{nil}
+	}
+
+	embeds := make([]interface{}, 0, len(matches))
+
+	for i, match := range matches {
+		matchData := *data
+		matchData.Match = match
+
+		title, err := this.renderTemplate(this.EmbedTitle, &matchData)
+		if err != nil {
+			return nil, err
+		}
+		if title == "" {
+			title = evalContext.GetNotificationTitle()
+		}
+
+		description, err := this.renderTemplate(this.EmbedDescription, &matchData)
+		if err != nil {
+			return nil, err
+		}
+		if description == "" {
+			description = evalContext.Rule.Message
+		}
+
+		embedURL, err := this.renderTemplate(this.EmbedURL, &matchData)
+		if err != nil {
+			return nil, err
+		}
+		if embedURL == "" {
+			embedURL = ruleUrl
+		}
+
+		embed := simplejson.New()
+		embed.Set("title", title)
+		//Discord takes integer for color
+		embed.Set("color", color)
+		embed.Set("url", embedURL)
+		embed.Set("description", description)
+		embed.Set("type", "rich")
+		embed.Set("footer", footer)
+
+		if match != nil {
+			embed.Set("fields", []map[string]interface{}{
+				{
+					"name":   match.Metric,
+					"value":  match.Value.FullString(),
+					"inline": true,
+				},
+			})
+		}
+
+		if i == 0 {
+			if evalContext.ImagePublicUrl != "" {
+				embed.Set("image", map[string]interface{}{"url": evalContext.ImagePublicUrl})
+			} else {
+				embed.Set("image", map[string]interface{}{"url": "attachment://graph.png"})
+			}
+		}
+
+		embeds = append(embeds, embed)
+	}
+
+	batches := make([][]interface{}, 0, (len(embeds)+discordEmbedLimit-1)/discordEmbedLimit)
+	for len(embeds) > 0 {
+		n := discordEmbedLimit
+		if n > len(embeds) {
+			n = len(embeds)
+		}
+		batches = append(batches, embeds[:n])
+		embeds = embeds[n:]
+	}
+
+	return batches, nil
+}
+
+// openImageFile returns a factory that opens the rendered panel for
+// streaming, or nil with no error if the render hasn't been written to disk
+// (e.g. rendering is disabled). The file itself is only opened lazily, at
+// dispatch time, when the payload's body reader factory is called, so the
+// 2MB+ PNGs this guards against are never
+// read into a []byte here.
+func openImageFile(imagePath string) (func() (io.ReadCloser, error), error) {
+	if _, err := os.Stat(imagePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return func() (io.ReadCloser, error) {
+		return os.Open(imagePath)
+	}, nil
 }
@@ -0,0 +1,225 @@
+package notifiers
+
+import (
+	"io"
+	"mime/multipart"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// discordPayload builds a Discord webhook/bot API message body
+// incrementally, so each concern - embeds, mentions, components, the
+// attached panel image - can be composed and unit tested independently of
+// Notify's control flow.
+type discordPayload struct {
+	username        string
+	avatarURL       string
+	content         string
+	threadName      string
+	embeds          []interface{}
+	allowedMentions map[string]interface{}
+	components      []interface{}
+	attachmentName  string
+	attachmentOpen  func() (io.ReadCloser, error)
+}
+
+func newDiscordPayload(username string) *discordPayload {
+	return &discordPayload{username: username}
+}
+
+func (p *discordPayload) WithAvatarURL(avatarURL string) *discordPayload {
+	p.avatarURL = avatarURL
+	return p
+}
+
+func (p *discordPayload) WithContent(content string) *discordPayload {
+	p.content = content
+	return p
+}
+
+func (p *discordPayload) WithThreadName(threadName string) *discordPayload {
+	p.threadName = threadName
+	return p
+}
+
+func (p *discordPayload) WithEmbed(embed interface{}) *discordPayload {
+	p.embeds = append(p.embeds, embed)
+	return p
+}
+
+// WithMentions sets the allowed_mentions whitelist so a notifier's
+// free-form content can only ping the given users/roles (and @everyone/@here
+// when everyone is true), instead of Discord honouring every mention found
+// in the message text.
+func (p *discordPayload) WithMentions(users []string, roles []string, everyone bool) *discordPayload {
+	parse := []string{}
+	if everyone {
+		parse = append(parse, "everyone")
+	}
+
+	allowed := map[string]interface{}{
+		"parse": parse,
+	}
+	if len(users) > 0 {
+		allowed["users"] = users
+	}
+	if len(roles) > 0 {
+		allowed["roles"] = roles
+	}
+
+	p.allowedMentions = allowed
+	return p
+}
+
+func (p *discordPayload) WithComponents(components []interface{}) *discordPayload {
+	p.components = components
+	return p
+}
+
+// WithAttachment attaches a file whose content is produced by open. Marshal
+// does not call open; it is called lazily each time the bodyReaderFactory
+// returned by Marshal is invoked, instead of the caller reading the file
+// into memory up front.
+func (p *discordPayload) WithAttachment(name string, open func() (io.ReadCloser, error)) *discordPayload {
+	p.attachmentName = name
+	p.attachmentOpen = open
+	return p
+}
+
+// AsEditPayload returns a copy of p with the webhook-creation-time-only
+// fields - username, avatar_url and thread_name - stripped. Discord's
+// PATCH /webhooks/{id}/{token}/messages/{message_id} edit endpoint rejects
+// those fields, so notifyWithMessageEdit must marshal this instead of the
+// payload built for the initial POST.
+func (p *discordPayload) AsEditPayload() *discordPayload {
+	edit := *p
+	edit.username = ""
+	edit.avatarURL = ""
+	edit.threadName = ""
+	return &edit
+}
+
+func (p *discordPayload) asJSON() *simplejson.Json {
+	body := simplejson.New()
+
+	if p.username != "" {
+		body.Set("username", p.username)
+	}
+	if p.avatarURL != "" {
+		body.Set("avatar_url", p.avatarURL)
+	}
+	if p.content != "" {
+		body.Set("content", p.content)
+	}
+	if p.threadName != "" {
+		body.Set("thread_name", p.threadName)
+	}
+	if len(p.embeds) > 0 {
+		body.Set("embeds", p.embeds)
+	}
+	if p.allowedMentions != nil {
+		body.Set("allowed_mentions", p.allowedMentions)
+	}
+	if len(p.components) > 0 {
+		body.Set("components", p.components)
+	}
+
+	return body
+}
+
+// Marshal renders the payload as a plain in-memory JSON body when there's no
+// attachment. When WithAttachment has been called, body is empty and
+// bodyReaderFactory is set instead: calling it streams the payload_json
+// field and the attached file straight into the multipart body via
+// io.Pipe, so the image is read once from disk and never buffered whole in
+// memory or copied into a string.
+func (p *discordPayload) Marshal() (body string, bodyReaderFactory func() (io.ReadCloser, error), contentType string, err error) {
+	json, err := p.asJSON().MarshalJSON()
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	if p.attachmentOpen == nil {
+		return string(json), nil, "application/json", nil
+	}
+
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	contentType = "multipart/form-data; boundary=" + boundary
+	attachmentName := p.attachmentName
+	open := p.attachmentOpen
+
+	bodyReaderFactory = func() (io.ReadCloser, error) {
+		f, err := open()
+		if err != nil {
+			return nil, err
+		}
+
+		pr, pw := io.Pipe()
+		w := multipart.NewWriter(pw)
+		if err := w.SetBoundary(boundary); err != nil {
+			f.Close()
+			pw.Close()
+			return nil, err
+		}
+
+		go func() {
+			defer f.Close()
+
+			fw, err := w.CreateFormField("payload_json")
+			if err == nil {
+				_, err = fw.Write(json)
+			}
+			if err == nil {
+				fw, err = w.CreateFormFile("file", attachmentName)
+			}
+			if err == nil {
+				_, err = io.Copy(fw, f)
+			}
+			if err == nil {
+				err = w.Close()
+			}
+
+			pw.CloseWithError(err)
+		}()
+
+		return pr, nil
+	}
+
+	return "", bodyReaderFactory, contentType, nil
+}
+
+// buildActionComponents renders a single action row of url-style (style 5)
+// buttons, Discord's only button style that opens a link without requiring
+// a bot backend to handle the interaction.
+//
+// There is deliberately no "Silence" button here: that would need a
+// /api/alerts/{id}/pause (or similar) endpoint to link to, and Grafana
+// doesn't expose one today. Add it back once that endpoint exists.
+func buildActionComponents(ruleURL, imageURL string) []interface{} {
+	ackURL := imageURL
+	if ackURL == "" {
+		ackURL = ruleURL
+	}
+
+	buttons := []interface{}{
+		map[string]interface{}{
+			"type":  2,
+			"style": 5,
+			"label": "Acknowledge",
+			"url":   ackURL,
+		},
+		map[string]interface{}{
+			"type":  2,
+			"style": 5,
+			"label": "Open in Grafana",
+			"url":   ruleURL,
+		},
+	}
+
+	return []interface{}{
+		map[string]interface{}{
+			"type":       1,
+			"components": buttons,
+		},
+	}
+}
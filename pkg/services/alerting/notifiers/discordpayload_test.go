@@ -0,0 +1,312 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiscordPayloadWithEmbed(t *testing.T) {
+	payload := newDiscordPayload("Grafana").
+		WithEmbed(map[string]interface{}{"title": "first"}).
+		WithEmbed(map[string]interface{}{"title": "second"})
+
+	body, bodyReaderFactory, contentType, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+	if bodyReaderFactory != nil {
+		t.Fatal("expected no bodyReaderFactory without an attachment")
+	}
+	if contentType != "application/json" {
+		t.Fatalf("expected application/json content type, got %q", contentType)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("body is not valid JSON: %s", err)
+	}
+
+	embeds, ok := decoded["embeds"].([]interface{})
+	if !ok || len(embeds) != 2 {
+		t.Fatalf("expected 2 embeds, got %#v", decoded["embeds"])
+	}
+}
+
+func TestDiscordPayloadWithMentions(t *testing.T) {
+	tests := []struct {
+		name      string
+		users     []string
+		roles     []string
+		everyone  bool
+		wantParse []interface{}
+		wantUsers []interface{}
+		wantRoles []interface{}
+	}{
+		{
+			name:      "no opt-in is restrictive by default",
+			wantParse: []interface{}{},
+		},
+		{
+			name:      "everyone whitelists the everyone parse type",
+			everyone:  true,
+			wantParse: []interface{}{"everyone"},
+		},
+		{
+			name:      "users and roles are whitelisted explicitly",
+			users:     []string{"u1", "u2"},
+			roles:     []string{"r1"},
+			wantParse: []interface{}{},
+			wantUsers: []interface{}{"u1", "u2"},
+			wantRoles: []interface{}{"r1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := newDiscordPayload("Grafana").WithMentions(tt.users, tt.roles, tt.everyone)
+
+			body, _, _, err := payload.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal returned error: %s", err)
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+				t.Fatalf("body is not valid JSON: %s", err)
+			}
+
+			allowed, ok := decoded["allowed_mentions"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected allowed_mentions to always be set, got %#v", decoded["allowed_mentions"])
+			}
+
+			parse, _ := allowed["parse"].([]interface{})
+			if len(parse) != len(tt.wantParse) {
+				t.Fatalf("parse = %#v, want %#v", parse, tt.wantParse)
+			}
+			for i := range parse {
+				if parse[i] != tt.wantParse[i] {
+					t.Fatalf("parse = %#v, want %#v", parse, tt.wantParse)
+				}
+			}
+
+			if tt.wantUsers != nil && !interfaceSlicesEqual(allowed["users"], tt.wantUsers) {
+				t.Fatalf("users = %#v, want %#v", allowed["users"], tt.wantUsers)
+			}
+			if tt.wantUsers == nil {
+				if _, ok := allowed["users"]; ok {
+					t.Fatalf("expected no users key, got %#v", allowed["users"])
+				}
+			}
+
+			if tt.wantRoles != nil && !interfaceSlicesEqual(allowed["roles"], tt.wantRoles) {
+				t.Fatalf("roles = %#v, want %#v", allowed["roles"], tt.wantRoles)
+			}
+			if tt.wantRoles == nil {
+				if _, ok := allowed["roles"]; ok {
+					t.Fatalf("expected no roles key, got %#v", allowed["roles"])
+				}
+			}
+		})
+	}
+}
+
+func interfaceSlicesEqual(got interface{}, want []interface{}) bool {
+	gotSlice, ok := got.([]interface{})
+	if !ok || len(gotSlice) != len(want) {
+		return false
+	}
+	for i := range gotSlice {
+		if gotSlice[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiscordPayloadWithComponents(t *testing.T) {
+	components := buildActionComponents("https://grafana.example/d/abc", "")
+	payload := newDiscordPayload("Grafana").WithComponents(components)
+
+	body, _, _, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("body is not valid JSON: %s", err)
+	}
+
+	rows, ok := decoded["components"].([]interface{})
+	if !ok || len(rows) != 1 {
+		t.Fatalf("expected a single action row, got %#v", decoded["components"])
+	}
+}
+
+func TestDiscordPayloadAsEditPayload(t *testing.T) {
+	payload := newDiscordPayload("Grafana").
+		WithAvatarURL("https://grafana.example/avatar.png").
+		WithContent("something happened").
+		WithThreadName("incident-1").
+		WithEmbed(map[string]interface{}{"title": "first"})
+
+	body, _, _, err := payload.AsEditPayload().Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("body is not valid JSON: %s", err)
+	}
+
+	for _, key := range []string{"username", "avatar_url", "thread_name"} {
+		if _, ok := decoded[key]; ok {
+			t.Fatalf("expected %q to be stripped from the edit payload, got %#v", key, decoded[key])
+		}
+	}
+	if decoded["content"] != "something happened" {
+		t.Fatalf("content = %#v, want it preserved", decoded["content"])
+	}
+	if embeds, ok := decoded["embeds"].([]interface{}); !ok || len(embeds) != 1 {
+		t.Fatalf("expected embeds to be preserved, got %#v", decoded["embeds"])
+	}
+
+	// The original payload must be left untouched.
+	origBody, _, _, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+	var origDecoded map[string]interface{}
+	if err := json.Unmarshal([]byte(origBody), &origDecoded); err != nil {
+		t.Fatalf("body is not valid JSON: %s", err)
+	}
+	if origDecoded["username"] != "Grafana" {
+		t.Fatalf("original payload's username was mutated: %#v", origDecoded["username"])
+	}
+}
+
+func TestDiscordPayloadMarshalWithAttachment(t *testing.T) {
+	const attachmentContent = "fake-png-bytes"
+
+	payload := newDiscordPayload("Grafana").
+		WithContent("something happened").
+		WithAttachment("panel.png", func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(attachmentContent)), nil
+		})
+
+	body, bodyReaderFactory, contentType, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+	if body != "" {
+		t.Fatalf("expected an empty body when streaming an attachment, got %q", body)
+	}
+	if bodyReaderFactory == nil {
+		t.Fatal("expected a non-nil bodyReaderFactory when an attachment is set")
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("contentType %q is not a valid media type: %s", contentType, err)
+	}
+	boundary, ok := params["boundary"]
+	if !ok {
+		t.Fatalf("contentType %q has no boundary", contentType)
+	}
+
+	rc, err := bodyReaderFactory()
+	if err != nil {
+		t.Fatalf("bodyReaderFactory returned error: %s", err)
+	}
+	defer rc.Close()
+
+	reader := multipart.NewReader(rc, boundary)
+
+	part, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("reading payload_json part: %s", err)
+	}
+	if part.FormName() != "payload_json" {
+		t.Fatalf("expected first part to be payload_json, got %q", part.FormName())
+	}
+	payloadJSON, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatalf("reading payload_json content: %s", err)
+	}
+	var decodedPayload map[string]interface{}
+	if err := json.Unmarshal(payloadJSON, &decodedPayload); err != nil {
+		t.Fatalf("payload_json is not valid JSON: %s", err)
+	}
+	if decodedPayload["content"] != "something happened" {
+		t.Fatalf("content = %#v, want %q", decodedPayload["content"], "something happened")
+	}
+
+	part, err = reader.NextPart()
+	if err != nil {
+		t.Fatalf("reading file part: %s", err)
+	}
+	if part.FormName() != "file" || part.FileName() != "panel.png" {
+		t.Fatalf("expected a file part named panel.png, got form=%q file=%q", part.FormName(), part.FileName())
+	}
+	fileContent, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatalf("reading file content: %s", err)
+	}
+	if string(fileContent) != attachmentContent {
+		t.Fatalf("file content = %q, want %q", fileContent, attachmentContent)
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("expected exactly two parts, got extra part (err=%v)", err)
+	}
+}
+
+// BenchmarkDiscordPayloadMarshalWithAttachment exercises Marshal's streaming
+// path against a 2MB+ attachment: the bodyReaderFactory should read the file
+// once and copy it straight into the multipart body without ever holding the
+// whole image in a buffered []byte or string.
+func BenchmarkDiscordPayloadMarshalWithAttachment(b *testing.B) {
+	f, err := os.CreateTemp("", "discordpayload-bench-*.png")
+	if err != nil {
+		b.Fatalf("creating temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	const attachmentSize = 3 << 20 // 3MB
+	if _, err := f.Write(make([]byte, attachmentSize)); err != nil {
+		b.Fatalf("writing temp file: %s", err)
+	}
+	f.Close()
+
+	b.ReportAllocs()
+	b.SetBytes(attachmentSize)
+
+	for i := 0; i < b.N; i++ {
+		payload := newDiscordPayload("Grafana").
+			WithContent("something happened").
+			WithAttachment("panel.png", func() (io.ReadCloser, error) {
+				return os.Open(f.Name())
+			})
+
+		_, bodyReaderFactory, _, err := payload.Marshal()
+		if err != nil {
+			b.Fatalf("Marshal returned error: %s", err)
+		}
+
+		rc, err := bodyReaderFactory()
+		if err != nil {
+			b.Fatalf("bodyReaderFactory returned error: %s", err)
+		}
+		if _, err := io.Copy(io.Discard, rc); err != nil {
+			b.Fatalf("draining body: %s", err)
+		}
+		rc.Close()
+	}
+}
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+var webhookLogger = log.New("notifications.webhook")
+
+// webhookClient is shared across every webhook dispatch (Slack, Discord,
+// Teams, ...) instead of each notifier building its own http.Client, so TCP
+// connections and TLS sessions get reused under concurrent/frequent alert
+// firing rather than paying a fresh handshake per notification.
+var webhookClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+func init() {
+	bus.AddHandlerCtx("notifications", sendWebhookSync)
+	bus.AddHandlerCtx("notifications", sendWebhook)
+}
+
+func sendWebhookSync(ctx context.Context, cmd *models.SendWebhookSync) error {
+	resp, err := doWebhookRequest(ctx, cmd.Url, cmd.HttpMethod, cmd.ContentType, cmd.Body, cmd.BodyReader, cmd.BodyReaderFactory, cmd.HttpHeader, cmd.User, cmd.Password)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	cmd.ResponseStatusCode = resp.StatusCode
+	cmd.ResponseBody = string(respBody)
+	cmd.ResponseHeader = resp.Header
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook dispatch to %s failed with status %d: %s", cmd.Url, resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+func sendWebhook(ctx context.Context, cmd *models.SendWebhook) error {
+	resp, err := doWebhookRequest(ctx, cmd.Url, cmd.HttpMethod, cmd.ContentType, cmd.Body, cmd.BodyReader, cmd.BodyReaderFactory, cmd.HttpHeader, cmd.User, cmd.Password)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		webhookLogger.Error("Async webhook dispatch failed", "url", cmd.Url, "status", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func doWebhookRequest(ctx context.Context, url string, method string, contentType string, body string, bodyReader io.Reader, bodyReaderFactory func() (io.ReadCloser, error), header map[string]string, user string, password string) (*http.Response, error) {
+	if method == "" {
+		method = "POST"
+	}
+
+	var reqBody io.Reader
+	switch {
+	case bodyReaderFactory != nil:
+		rc, err := bodyReaderFactory()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = rc
+	case bodyReader != nil:
+		reqBody = bodyReader
+	default:
+		reqBody = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+
+	return webhookClient.Do(req)
+}
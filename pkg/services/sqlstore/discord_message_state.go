@@ -0,0 +1,60 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandlerCtx("sqlstore", GetDiscordMessageState)
+	bus.AddHandlerCtx("sqlstore", SetDiscordMessageState)
+}
+
+// GetDiscordMessageState looks up the alert_notification_state row for this
+// (org, rule, notifier), if one has been recorded.
+func GetDiscordMessageState(ctx context.Context, query *models.GetDiscordMessageStateQuery) error {
+	return withDbSession(ctx, func(sess *DBSession) error {
+		var state models.DiscordMessageState
+		has, err := sess.Table("alert_notification_state").Where("org_id=? AND rule_id=? AND notifier_id=?", query.OrgId, query.RuleId, query.NotifierId).
+			Get(&state)
+		if err != nil {
+			return err
+		}
+		if has {
+			query.Result = &state
+		}
+
+		return nil
+	})
+}
+
+// SetDiscordMessageState upserts the message id for this (org, rule,
+// notifier), so the next notification can PATCH it instead of posting a new
+// message.
+func SetDiscordMessageState(ctx context.Context, cmd *models.SetDiscordMessageStateCommand) error {
+	return withDbSession(ctx, func(sess *DBSession) error {
+		var existing models.DiscordMessageState
+		has, err := sess.Table("alert_notification_state").Where("org_id=? AND rule_id=? AND notifier_id=?", cmd.OrgId, cmd.RuleId, cmd.NotifierId).
+			Get(&existing)
+		if err != nil {
+			return err
+		}
+
+		if has {
+			existing.MessageId = cmd.MessageId
+			_, err := sess.Table("alert_notification_state").ID(existing.Id).Cols("message_id").Update(&existing)
+			return err
+		}
+
+		state := &models.DiscordMessageState{
+			OrgId:      cmd.OrgId,
+			RuleId:     cmd.RuleId,
+			NotifierId: cmd.NotifierId,
+			MessageId:  cmd.MessageId,
+		}
+		_, err = sess.Table("alert_notification_state").Insert(state)
+		return err
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// addDiscordMessageStateMigrations creates the alert_notification_state
+// table backing the Discord notifier's "update existing message" mode.
+//
+// This needs to be wired into migrations.go's AddMigrations alongside the
+// other addXMigrations calls; migrations.go itself isn't part of this diff,
+// since it already lists every historical migration for the full monorepo.
+func addDiscordMessageStateMigrations(mg *Migrator) {
+	alertNotificationState := Table{
+		Name: "alert_notification_state",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "rule_id", Type: DB_BigInt, Nullable: false},
+			{Name: "notifier_id", Type: DB_BigInt, Nullable: false},
+			{Name: "message_id", Type: DB_NVarchar, Length: 64, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "rule_id", "notifier_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create alert_notification_state table", NewAddTableMigration(alertNotificationState))
+	mg.AddMigration(
+		"add unique index alert_notification_state.org_id_rule_id_notifier_id",
+		NewAddIndexMigration(alertNotificationState, alertNotificationState.Indices[0]),
+	)
+}